@@ -0,0 +1,70 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+// Flavor is the dialect of SQL emitted by a builder.
+type Flavor int
+
+// Flavor constants. MySQL, PostgreSQL, SQLite, SQLServer, CQL, ClickHouse,
+// Presto, Oracle, Informix and Doris are the flavors supported upstream;
+// Sphinx is appended for SphinxQL (Manticore/Sphinx real-time indexes).
+const (
+	invalidFlavor Flavor = iota
+	MySQL
+	PostgreSQL
+	SQLite
+	SQLServer
+	CQL
+	ClickHouse
+	Presto
+	Oracle
+	Informix
+	Doris
+	Sphinx
+)
+
+// DefaultFlavor is the flavor used by package-level builder constructors
+// such as NewInsertBuilder, InsertInto and InsertIgnoreInto.
+var DefaultFlavor = MySQL
+
+// NewInsertBuilder creates a new INSERT builder for flavor f.
+func (f Flavor) NewInsertBuilder() *InsertBuilder {
+	ib := newInsertBuilder()
+	ib.args.Flavor = f
+	return ib
+}
+
+// PrepareInsertIgnore rewrites ib into f's equivalent of
+// INSERT IGNORE INTO table. SphinxQL has no such equivalent; it returns
+// ErrSphinxInsertIgnoreUnsupported and leaves ib usable as a plain INSERT.
+func (f Flavor) PrepareInsertIgnore(table string, ib *InsertBuilder) error {
+	ib.table = Escape(table)
+	ib.marker = insertMarkerAfterInsertInto
+
+	switch f {
+	case MySQL:
+		ib.verb = "INSERT IGNORE"
+		return nil
+	case Sphinx:
+		return ErrSphinxInsertIgnoreUnsupported
+	default:
+		ib.verb = "INSERT"
+		return nil
+	}
+}
+
+// MaxPlaceholders returns the maximum number of bound parameters a single
+// statement may contain for f, used by InsertBuilder.BuildBatches to keep
+// each chunk under the driver's limit. Flavors with no documented cap
+// return 0, meaning ChunkSize alone decides the chunk size.
+func (f Flavor) MaxPlaceholders() int {
+	switch f {
+	case PostgreSQL:
+		return 65535
+	case SQLite:
+		return 32766
+	default:
+		return 0
+	}
+}
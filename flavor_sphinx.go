@@ -0,0 +1,44 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+// Sphinx support targets SphinxQL as spoken by Manticore Search and Sphinx
+// real-time (rt_) indexes. Table and column names go through the same
+// Escape/EscapeAll InsertBuilder already uses for every other flavor;
+// unlike other flavors' quoting, that just escapes a literal "$" and
+// leaves the identifier otherwise bare -- SphinxQL has no backtick quoting
+// of its own, so emitting bare identifiers is correct as-is and no
+// flavor-specific quoting is needed. Upserts reuse ReplaceInto: SphinxQL
+// has no native ON DUPLICATE KEY UPDATE/ON CONFLICT, so
+// ReplaceInto(table).Cols(...).Values(...) against a rt_ index is the
+// idiomatic way to upsert a document by its id.
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrSphinxInsertIgnoreUnsupported is returned by Flavor.PrepareInsertIgnore
+// for Sphinx: SphinxQL has no INSERT IGNORE equivalent, so callers must
+// check InsertBuilder.Err() after InsertIgnoreInto.
+var ErrSphinxInsertIgnoreUnsupported = errors.New("sqlbuilder: SphinxQL does not support INSERT IGNORE")
+
+// mvaValue is the type of the sentinel returned by MVA. InsertBuilder.Values
+// recognizes it and writes it inline instead of binding it as an argument.
+type mvaValue string
+
+// MVA returns a sentinel for InsertBuilder.Values that renders vals as an
+// inline SphinxQL multi-value attribute literal, e.g. (1,2,3), instead of
+// binding them as a parameter -- SphinxQL doesn't support parameterized MVA
+// columns.
+func MVA(vals []uint64) mvaValue {
+	parts := make([]string, len(vals))
+
+	for i, v := range vals {
+		parts[i] = strconv.FormatUint(v, 10)
+	}
+
+	return mvaValue("(" + strings.Join(parts, ",") + ")")
+}
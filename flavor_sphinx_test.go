@@ -0,0 +1,33 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMVARendersInline(t *testing.T) {
+	ib := Sphinx.NewInsertBuilder()
+	ib.InsertInto("t").Cols("id", "tags").Values(1, MVA([]uint64{1, 2, 3}))
+
+	sql, args := ib.Build()
+
+	want := "INSERT INTO t (id, tags) VALUES (?, (1,2,3))"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+	if len(args) != 1 || args[0] != 1 {
+		t.Fatalf("args = %v, want [1]; MVA must not be bound as an argument", args)
+	}
+}
+
+func TestSphinxInsertIgnoreUnsupported(t *testing.T) {
+	ib := Sphinx.NewInsertBuilder()
+	ib.InsertIgnoreInto("t")
+
+	if !errors.Is(ib.Err(), ErrSphinxInsertIgnoreUnsupported) {
+		t.Fatalf("Err() = %v, want ErrSphinxInsertIgnoreUnsupported", ib.Err())
+	}
+}
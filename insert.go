@@ -6,6 +6,8 @@ package sqlbuilder
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -14,8 +16,50 @@ const (
 	insertMarkerAfterInsertInto
 	insertMarkerAfterCols
 	insertMarkerAfterValues
+	insertMarkerAfterSelect
+	insertMarkerAfterOnConflict
+	insertMarkerAfterReturning
 )
 
+// defaultValueSentinel is the type of Default.
+type defaultValueSentinel struct{}
+
+// Default is a sentinel value for InsertBuilder.Values, e.g.
+// ib.Values(1, Default, "x"), that renders as the literal DEFAULT keyword
+// instead of being bound as an argument.
+var Default = defaultValueSentinel{}
+
+// valuesFuncPattern matches the marker left behind by Values, so the
+// assignments passed to OnDuplicateKeyUpdate/DoUpdate can be rendered for
+// the right flavor at build time.
+var valuesFuncPattern = regexp.MustCompile("\x00VALUES:([^\x00]*)\x00")
+
+// Values returns a reference to the value proposed for insertion for col,
+// for use inside an OnDuplicateKeyUpdate or DoUpdate assignment, e.g.
+// "price = "+Values("price"). It renders as VALUES(col) on MySQL and
+// EXCLUDED.col on PostgreSQL/SQLite.
+func Values(col string) string {
+	return "\x00VALUES:" + col + "\x00"
+}
+
+func resolveValuesFunc(assignments []string, flavor Flavor) []string {
+	resolved := make([]string, len(assignments))
+
+	for i, assignment := range assignments {
+		resolved[i] = valuesFuncPattern.ReplaceAllStringFunc(assignment, func(m string) string {
+			col := valuesFuncPattern.FindStringSubmatch(m)[1]
+
+			if flavor == MySQL {
+				return fmt.Sprintf("VALUES(%s)", col)
+			}
+
+			return "EXCLUDED." + col
+		})
+	}
+
+	return resolved
+}
+
 // NewInsertBuilder creates a new INSERT builder.
 func NewInsertBuilder() *InsertBuilder {
 	return DefaultFlavor.NewInsertBuilder()
@@ -32,10 +76,28 @@ func newInsertBuilder() *InsertBuilder {
 
 // InsertBuilder is a builder to build INSERT.
 type InsertBuilder struct {
-	verb   string
-	table  string
-	cols   []string
-	values [][]string
+	verb       string
+	table      string
+	cols       []string
+	values     [][]string
+	fromSelect *SelectBuilder
+	useDefault bool
+
+	duplicateKeyUpdate []string
+	conflictCols       []string
+	conflictAction     string
+	conflictUpdate     []string
+
+	returningCols []string
+
+	chunkSize int
+	batchCols []string
+	batchRows [][]interface{}
+
+	mapper   *MetaRegistry
+	omitZero bool
+
+	err error
 
 	args *Args
 
@@ -62,12 +124,24 @@ func InsertIgnoreInto(table string) *InsertBuilder {
 	return DefaultFlavor.NewInsertBuilder().InsertIgnoreInto(table)
 }
 
-// InsertIgnoreInto sets table name in INSERT IGNORE.
+// InsertIgnoreInto sets table name in INSERT IGNORE. Not all flavors
+// support this; if ib's flavor doesn't, the error is recorded and can be
+// retrieved with Err -- SphinxQL is one such flavor, since it has no
+// INSERT IGNORE equivalent.
 func (ib *InsertBuilder) InsertIgnoreInto(table string) *InsertBuilder {
-	ib.args.Flavor.PrepareInsertIgnore(table, ib)
+	if err := ib.args.Flavor.PrepareInsertIgnore(table, ib); err != nil {
+		ib.err = err
+	}
 	return ib
 }
 
+// Err returns the most recent error recorded while building ib, for example from
+// calling InsertIgnoreInto with a flavor that doesn't support INSERT
+// IGNORE.
+func (ib *InsertBuilder) Err() error {
+	return ib.err
+}
+
 // ReplaceInto sets table name and changes the verb of ib to REPLACE.
 // REPLACE INTO is a MySQL extension to the SQL standard.
 func ReplaceInto(table string) *InsertBuilder {
@@ -84,36 +158,265 @@ func (ib *InsertBuilder) ReplaceInto(table string) *InsertBuilder {
 }
 
 func (ib *InsertBuilder) InsertItem(item interface{}) *InsertBuilder {
-	var cols []string
-	var values []interface{}
+	cols, values := ib.insertItemFields(item)
+	ib.Cols(cols...).Values(values...)
+	return ib
+}
+
+// WithTagName makes InsertItem/InsertItems read struct tags named tagName
+// (e.g. "gorm", "xorm") instead of "db".
+func (ib *InsertBuilder) WithTagName(tagName string) *InsertBuilder {
+	ib.mapper = NewMetaRegistry(tagName)
+	return ib
+}
+
+// OmitZero makes InsertItem/InsertItems skip zero-valued fields, as if they
+// were all tagged "omitempty".
+func (ib *InsertBuilder) OmitZero() *InsertBuilder {
+	ib.omitZero = true
+	return ib
+}
 
-	valueType := reflect.TypeOf(item)
-	valueData := reflect.ValueOf(item)
+func (ib *InsertBuilder) metaRegistry() *MetaRegistry {
+	if ib.mapper != nil {
+		return ib.mapper
+	}
 
-	// 检查是否是指针类型
-	if valueType.Kind() == reflect.Ptr {
-		// 获取指针所指向的实际类型
-		valueType = valueType.Elem()
-		valueData = valueData.Elem()
+	return DefaultMetaRegistry
+}
+
+// insertItemFields uses ib's MetaRegistry to derive the columns and values
+// InsertItem inserts for item, skipping primary_key and auto_increment
+// fields and, when OmitZero or the field's own "omitempty" tag applies,
+// zero values -- unless the field also carries "default=...", in which
+// case the column is kept and Default is substituted for the zero value.
+func (ib *InsertBuilder) insertItemFields(item interface{}) (cols []string, values []interface{}) {
+	v := reflect.ValueOf(item)
+	t := v.Type()
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		v = v.Elem()
 	}
 
-	// 构建插入语句
-	for i := 0; i < valueType.NumField(); i++ {
-		dbTag := valueType.Field(i).Tag.Get("db")
-		dbList := strings.Split(dbTag, ";")
-		// if primary_key in dbTag, skip
-		if len(dbList) > 1 && dbList[1] == "primary_key" {
+	meta := ib.metaRegistry().MetaFor(t)
+
+	for _, fm := range meta.Fields {
+		// Matches the pre-registry behavior: a primary_key field is never
+		// inserted, whether or not it's also auto_increment.
+		if fm.PrimaryKey || fm.AutoIncrement {
 			continue
 		}
-		if len(dbList) > 0 {
-			cols = append(cols, dbList[0])
-			values = append(values, valueData.Field(i).Interface())
+
+		value, omit := fieldInsertValue(fm, v.FieldByIndex(fm.Index), ib.omitZero)
+		if omit {
+			continue
 		}
+
+		cols = append(cols, fm.Column)
+		values = append(values, value)
 	}
-	ib.Cols(cols...).Values(values...)
+
+	return cols, values
+}
+
+// fieldInsertValue returns the value fm's field should insert as, and
+// whether the column should be omitted entirely. A zero value is omitted
+// when OmitZero or fm's own "omitempty" applies, unless fm also carries
+// "default=...", in which case Default is returned instead of omitting the
+// column -- this is what lets a batch insert (see InsertItems) keep every
+// row's column count aligned despite per-row zero values.
+func fieldInsertValue(fm *StructFieldMeta, fv reflect.Value, omitZero bool) (value interface{}, omit bool) {
+	if (fm.OmitEmpty || omitZero) && fv.IsZero() {
+		if fm.Default != "" {
+			return Default, false
+		}
+		return nil, true
+	}
+
+	return fv.Interface(), false
+}
+
+// InsertItems adds one row per element of items, a slice of structs (using
+// the same "db" tag rules as InsertItem) or a []map[string]interface{}, and
+// compiles them into a single multi-row VALUES clause. Rows added this way
+// are also tracked for ChunkSize/BuildBatches, so a large batch can be split
+// across several statements.
+func (ib *InsertBuilder) InsertItems(items interface{}) *InsertBuilder {
+	if maps, ok := items.([]map[string]interface{}); ok {
+		if len(maps) == 0 {
+			return ib
+		}
+
+		// Columns are derived once from the first row and every row's
+		// values are read back in that same order, so map iteration order
+		// (which Go randomizes) can't misalign values against columns.
+		cols := make([]string, 0, len(maps[0]))
+		for col := range maps[0] {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+
+		for _, m := range maps {
+			values := make([]interface{}, len(cols))
+			for i, col := range cols {
+				values[i] = m[col]
+			}
+
+			ib.addBatchRow(cols, values)
+		}
+
+		return ib
+	}
+
+	v := reflect.ValueOf(items)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Len() == 0 {
+		return ib
+	}
+
+	// Unlike insertItemFields (used by the single-row InsertItem), columns
+	// here must be derived once, from the item type, rather than per row:
+	// insertItemFields omits a zero-valued "omitempty" field entirely, so
+	// deriving cols/values per row can give each row a different column
+	// count, and Values(values...) would then append a ragged row. Instead,
+	// every non-primary_key/auto_increment field becomes a column, and a
+	// row whose field would otherwise have been omitted gets the Default
+	// sentinel in that column instead, keeping every row the same width.
+	itemType := reflect.TypeOf(v.Index(0).Interface())
+	if itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	meta := ib.metaRegistry().MetaFor(itemType)
+
+	var cols []string
+	var fields []*StructFieldMeta
+	for _, fm := range meta.Fields {
+		if fm.PrimaryKey || fm.AutoIncrement {
+			continue
+		}
+
+		cols = append(cols, fm.Column)
+		fields = append(fields, fm)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		rv := reflect.ValueOf(v.Index(i).Interface())
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+
+		values := make([]interface{}, len(fields))
+		for j, fm := range fields {
+			value, omit := fieldInsertValue(fm, rv.FieldByIndex(fm.Index), ib.omitZero)
+			if omit {
+				value = Default
+			}
+			values[j] = value
+		}
+
+		ib.addBatchRow(cols, values)
+	}
+
+	return ib
+}
+
+// addBatchRow appends a row to both the compiled VALUES clause and the raw
+// batchRows used by BuildBatches to re-chunk the insert.
+func (ib *InsertBuilder) addBatchRow(cols []string, values []interface{}) {
+	if ib.batchCols == nil {
+		ib.batchCols = cols
+		ib.Cols(cols...)
+	}
+
+	ib.batchRows = append(ib.batchRows, values)
+	ib.Values(values...)
+}
+
+// ChunkSize caps the number of rows added via InsertItems that BuildBatches
+// puts into a single statement. The effective chunk size is also capped by
+// the flavor's MaxPlaceholders, whichever is smaller, since drivers such as
+// PostgreSQL (65535) and SQLite (32766) reject statements with too many
+// bound parameters.
+func (ib *InsertBuilder) ChunkSize(n int) *InsertBuilder {
+	ib.chunkSize = n
+	return ib
+}
+
+// Returning adds a RETURNING clause for flavors that can return the
+// inserted rows (PostgreSQL, SQLite). On SQL Server, which has no RETURNING
+// clause, the same columns are emitted as an OUTPUT INSERTED.* clause ahead
+// of VALUES instead.
+func (ib *InsertBuilder) Returning(col ...string) *InsertBuilder {
+	ib.returningCols = EscapeAll(col...)
+	ib.marker = insertMarkerAfterReturning
 	return ib
 }
 
+// Batch is one chunk of a batched INSERT produced by BuildBatches, ready to
+// run independently via `DB#Exec` or `DB#Query` of package `database/sql`.
+type Batch struct {
+	SQL  string
+	Args []interface{}
+}
+
+// BuildBatches splits the rows added via InsertItems into statements no
+// larger than ChunkSize (or the flavor's MaxPlaceholders, whichever is
+// smaller) and returns one Batch per chunk, so each can be executed in a
+// loop without exceeding the driver's bound parameter limit. If no rows
+// were added via InsertItems, it returns a single Batch equivalent to Build.
+func (ib *InsertBuilder) BuildBatches() []Batch {
+	return ib.buildBatchesWithFlavor(ib.args.Flavor)
+}
+
+func (ib *InsertBuilder) buildBatchesWithFlavor(flavor Flavor) []Batch {
+	if len(ib.batchRows) == 0 {
+		sql, args := ib.BuildWithFlavor(flavor)
+		return []Batch{{SQL: sql, Args: args}}
+	}
+
+	chunkSize := ib.chunkSize
+
+	if cols := len(ib.batchCols); cols > 0 {
+		if max := flavor.MaxPlaceholders() / cols; max > 0 && (chunkSize == 0 || max < chunkSize) {
+			chunkSize = max
+		}
+	}
+	if chunkSize <= 0 {
+		chunkSize = len(ib.batchRows)
+	}
+
+	batches := make([]Batch, 0, (len(ib.batchRows)+chunkSize-1)/chunkSize)
+
+	for start := 0; start < len(ib.batchRows); start += chunkSize {
+		end := start + chunkSize
+		if end > len(ib.batchRows) {
+			end = len(ib.batchRows)
+		}
+
+		chunk := newInsertBuilder()
+		chunk.verb = ib.verb
+		chunk.table = ib.table
+		chunk.cols = ib.cols
+		chunk.duplicateKeyUpdate = ib.duplicateKeyUpdate
+		chunk.conflictCols = ib.conflictCols
+		chunk.conflictAction = ib.conflictAction
+		chunk.conflictUpdate = ib.conflictUpdate
+		chunk.returningCols = ib.returningCols
+
+		for _, row := range ib.batchRows[start:end] {
+			chunk.Values(row...)
+		}
+
+		sql, args := chunk.BuildWithFlavor(flavor)
+		batches = append(batches, Batch{SQL: sql, Args: args})
+	}
+
+	return batches
+}
+
 // Cols sets columns in INSERT.
 func (ib *InsertBuilder) Cols(col ...string) *InsertBuilder {
 	ib.cols = EscapeAll(col...)
@@ -122,11 +425,22 @@ func (ib *InsertBuilder) Cols(col ...string) *InsertBuilder {
 }
 
 // Values adds a list of values for a row in INSERT.
+// It's mutually exclusive with Select: calling Values clears any sub-select
+// set by Select or From.
 func (ib *InsertBuilder) Values(value ...interface{}) *InsertBuilder {
+	ib.fromSelect = nil
+	ib.useDefault = false
 	placeholders := make([]string, 0, len(value))
 
 	for _, v := range value {
-		placeholders = append(placeholders, ib.args.Add(v))
+		switch sv := v.(type) {
+		case defaultValueSentinel:
+			placeholders = append(placeholders, "DEFAULT")
+		case mvaValue:
+			placeholders = append(placeholders, string(sv))
+		default:
+			placeholders = append(placeholders, ib.args.Add(v))
+		}
 	}
 
 	ib.values = append(ib.values, placeholders)
@@ -134,6 +448,85 @@ func (ib *InsertBuilder) Values(value ...interface{}) *InsertBuilder {
 	return ib
 }
 
+// DefaultValues clears any columns, values or sub-select previously set and
+// makes Build emit INSERT INTO t DEFAULT VALUES, or on flavors without that
+// syntax (MySQL) the equivalent INSERT INTO t () VALUES ().
+func (ib *InsertBuilder) DefaultValues() *InsertBuilder {
+	ib.cols = nil
+	ib.values = nil
+	ib.fromSelect = nil
+	ib.useDefault = true
+	ib.marker = insertMarkerAfterValues
+	return ib
+}
+
+// Select uses sb as a sub-SELECT providing the rows for INSERT, producing
+// INSERT INTO t (cols...) SELECT ... . It's mutually exclusive with Values:
+// calling Select clears any rows added by Values.
+func (ib *InsertBuilder) Select(sb *SelectBuilder) *InsertBuilder {
+	ib.values = nil
+	ib.useDefault = false
+	ib.fromSelect = sb
+	ib.marker = insertMarkerAfterSelect
+	return ib
+}
+
+// From is a shortcut for Select. It builds a SELECT of ib's columns from
+// table, so InsertInto("t").Cols("a", "b").From("src") produces
+// INSERT INTO t (a, b) SELECT a, b FROM src.
+func (ib *InsertBuilder) From(table string) *InsertBuilder {
+	sb := NewSelectBuilder()
+	sb.Select(ib.cols...)
+	sb.From(table)
+	return ib.Select(sb)
+}
+
+// OnDuplicateKeyUpdate adds a MySQL ON DUPLICATE KEY UPDATE clause with the
+// given assignments, built the same way as UpdateBuilder's SET clause, e.g.
+// assignment+"="+ib.Var(v). Use Values(col) inside an assignment to
+// reference the value that was proposed for insertion, e.g.
+// "views = "+Values("views")+" + "+ib.Var(1). It's only valid for MySQL; if
+// ib's flavor turns out to be something else, the mismatch is recorded and
+// can be retrieved with Err.
+func (ib *InsertBuilder) OnDuplicateKeyUpdate(assignment ...string) *InsertBuilder {
+	if ib.args.Flavor != MySQL {
+		ib.err = fmt.Errorf("sqlbuilder: OnDuplicateKeyUpdate is not supported by %v; use OnConflict instead", ib.args.Flavor)
+	}
+
+	ib.duplicateKeyUpdate = assignment
+	ib.marker = insertMarkerAfterOnConflict
+	return ib
+}
+
+// OnConflict starts a PostgreSQL/SQLite ON CONFLICT clause targeting col.
+// Chain DoUpdate or DoNothing to complete it. It's only valid outside
+// MySQL; if ib's flavor is MySQL, the mismatch is recorded and can be
+// retrieved with Err.
+func (ib *InsertBuilder) OnConflict(col ...string) *InsertBuilder {
+	if ib.args.Flavor == MySQL {
+		ib.err = fmt.Errorf("sqlbuilder: OnConflict is not supported by MySQL; use OnDuplicateKeyUpdate instead")
+	}
+
+	ib.conflictCols = EscapeAll(col...)
+	return ib
+}
+
+// DoUpdate completes an OnConflict clause with DO UPDATE SET assignment,
+// built the same way as OnDuplicateKeyUpdate.
+func (ib *InsertBuilder) DoUpdate(assignment ...string) *InsertBuilder {
+	ib.conflictAction = "update"
+	ib.conflictUpdate = assignment
+	ib.marker = insertMarkerAfterOnConflict
+	return ib
+}
+
+// DoNothing completes an OnConflict clause with DO NOTHING.
+func (ib *InsertBuilder) DoNothing() *InsertBuilder {
+	ib.conflictAction = "nothing"
+	ib.marker = insertMarkerAfterOnConflict
+	return ib
+}
+
 // String returns the compiled INSERT string.
 func (ib *InsertBuilder) String() string {
 	s, _ := ib.Build()
@@ -168,7 +561,27 @@ func (ib *InsertBuilder) BuildWithFlavor(flavor Flavor, initialArg ...interface{
 		ib.injection.WriteTo(buf, insertMarkerAfterCols)
 	}
 
-	if len(ib.values) > 0 {
+	if len(ib.returningCols) > 0 && flavor == SQLServer {
+		buf.WriteLeadingString("OUTPUT ")
+
+		outputCols := make([]string, len(ib.returningCols))
+		for i, col := range ib.returningCols {
+			outputCols[i] = "INSERTED." + col
+		}
+
+		buf.WriteString(strings.Join(outputCols, ", "))
+	}
+
+	if ib.useDefault {
+		if flavor == MySQL {
+			buf.WriteLeadingString("() VALUES ()")
+		} else {
+			buf.WriteLeadingString("DEFAULT VALUES")
+		}
+	} else if ib.fromSelect != nil {
+		buf.WriteLeadingString(ib.args.Add(ib.fromSelect))
+		ib.injection.WriteTo(buf, insertMarkerAfterSelect)
+	} else if len(ib.values) > 0 {
 		buf.WriteLeadingString("VALUES ")
 		values := make([]string, 0, len(ib.values))
 
@@ -181,6 +594,34 @@ func (ib *InsertBuilder) BuildWithFlavor(flavor Flavor, initialArg ...interface{
 
 	ib.injection.WriteTo(buf, insertMarkerAfterValues)
 
+	if flavor == MySQL {
+		if len(ib.duplicateKeyUpdate) > 0 {
+			buf.WriteLeadingString("ON DUPLICATE KEY UPDATE ")
+			buf.WriteString(strings.Join(resolveValuesFunc(ib.duplicateKeyUpdate, flavor), ", "))
+		}
+	} else if len(ib.conflictCols) > 0 {
+		buf.WriteLeadingString("ON CONFLICT (")
+		buf.WriteString(strings.Join(ib.conflictCols, ", "))
+		buf.WriteString(")")
+
+		switch ib.conflictAction {
+		case "update":
+			buf.WriteString(" DO UPDATE SET ")
+			buf.WriteString(strings.Join(resolveValuesFunc(ib.conflictUpdate, flavor), ", "))
+		case "nothing":
+			buf.WriteString(" DO NOTHING")
+		}
+	}
+
+	ib.injection.WriteTo(buf, insertMarkerAfterOnConflict)
+
+	if len(ib.returningCols) > 0 && flavor != SQLServer {
+		buf.WriteLeadingString("RETURNING ")
+		buf.WriteString(strings.Join(ib.returningCols, ", "))
+	}
+
+	ib.injection.WriteTo(buf, insertMarkerAfterReturning)
+
 	return ib.args.CompileWithFlavor(buf.String(), flavor, initialArg...)
 }
 
@@ -0,0 +1,275 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import "testing"
+
+func TestInsertSelect(t *testing.T) {
+	sb := NewSelectBuilder()
+	sb.Select("a", "b").From("src")
+
+	ib := NewInsertBuilder()
+	ib.InsertInto("dst").Cols("a", "b").Select(sb)
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO dst (a, b) SELECT a, b FROM src"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
+
+func TestInsertFromShortcut(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("dst").Cols("a", "b").From("src")
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO dst (a, b) SELECT a, b FROM src"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
+
+func TestOnDuplicateKeyUpdateRendersValuesFunc(t *testing.T) {
+	ib := MySQL.NewInsertBuilder()
+	ib.InsertInto("t").Cols("id", "price").Values(1, 2)
+	ib.OnDuplicateKeyUpdate("price = " + Values("price"))
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO t (id, price) VALUES (?, ?) ON DUPLICATE KEY UPDATE price = VALUES(price)"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+	if ib.Err() != nil {
+		t.Fatalf("Err() = %v, want nil", ib.Err())
+	}
+}
+
+func TestOnConflictRendersExcluded(t *testing.T) {
+	ib := PostgreSQL.NewInsertBuilder()
+	ib.InsertInto("t").Cols("id", "price").Values(1, 2)
+	ib.OnConflict("id").DoUpdate("price = " + Values("price"))
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO t (id, price) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET price = EXCLUDED.price"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+	if ib.Err() != nil {
+		t.Fatalf("Err() = %v, want nil", ib.Err())
+	}
+}
+
+func TestOnDuplicateKeyUpdateWrongFlavorRecordsErr(t *testing.T) {
+	ib := PostgreSQL.NewInsertBuilder()
+	ib.InsertInto("t").Cols("id").Values(1)
+	ib.OnDuplicateKeyUpdate("id = " + Values("id"))
+
+	if ib.Err() == nil {
+		t.Fatal("Err() = nil, want a mismatch error for OnDuplicateKeyUpdate on PostgreSQL")
+	}
+}
+
+func TestOnConflictWrongFlavorRecordsErr(t *testing.T) {
+	ib := MySQL.NewInsertBuilder()
+	ib.InsertInto("t").Cols("id").Values(1)
+	ib.OnConflict("id").DoNothing()
+
+	if ib.Err() == nil {
+		t.Fatal("Err() = nil, want a mismatch error for OnConflict on MySQL")
+	}
+}
+
+func TestBuildBatchesRespectsChunkSize(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("t")
+	ib.InsertItems([]map[string]interface{}{
+		{"a": 1}, {"a": 2}, {"a": 3}, {"a": 4}, {"a": 5},
+	})
+	ib.ChunkSize(2)
+
+	batches := ib.BuildBatches()
+
+	wantSizes := []int{2, 2, 1}
+	if len(batches) != len(wantSizes) {
+		t.Fatalf("len(batches) = %d, want %d", len(batches), len(wantSizes))
+	}
+	for i, b := range batches {
+		if len(b.Args) != wantSizes[i] {
+			t.Fatalf("batch %d has %d args, want %d", i, len(b.Args), wantSizes[i])
+		}
+	}
+}
+
+func TestBuildBatchesRespectsMaxPlaceholders(t *testing.T) {
+	// SQLite.MaxPlaceholders() caps at 32766; with 2 columns per row that's
+	// 16383 rows per statement. A ChunkSize bigger than that must still be
+	// capped down by MaxPlaceholders rather than taken at face value.
+	rows := make([]map[string]interface{}, 16385)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"a": i, "b": i}
+	}
+
+	ib := SQLite.NewInsertBuilder()
+	ib.InsertInto("t")
+	ib.InsertItems(rows)
+	ib.ChunkSize(1 << 20)
+
+	batches := ib.BuildBatches()
+	if len(batches) != 2 {
+		t.Fatalf("len(batches) = %d, want 2 (16385 rows split at the 16383-row/32766-placeholder cap)", len(batches))
+	}
+	if len(batches[0].Args) != 16383*2 {
+		t.Fatalf("len(batches[0].Args) = %d, want %d", len(batches[0].Args), 16383*2)
+	}
+}
+
+func TestInsertItemsMapBatchColumnAlignment(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("t")
+	ib.InsertItems([]map[string]interface{}{
+		{"a": 1, "b": 10},
+		{"a": 2, "b": 20},
+		{"a": 3, "b": 30},
+	})
+
+	_, args := ib.Build()
+
+	// Columns are sorted ("a", "b"), so args must interleave as a, b, a, b, ...
+	want := []interface{}{1, 10, 2, 20, 3, 30}
+	if len(args) != len(want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("args = %v, want %v (row/column misaligned at index %d)", args, want, i)
+		}
+	}
+}
+
+func TestValuesDefaultSentinel(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("t").Cols("id", "created_at", "name").Values(1, Default, "alice")
+
+	sql, args := ib.Build()
+
+	want := "INSERT INTO t (id, created_at, name) VALUES (?, DEFAULT, ?)"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+	if len(args) != 2 || args[0] != 1 || args[1] != "alice" {
+		t.Fatalf("args = %v, want [1 alice]; DEFAULT must not be bound as an argument", args)
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	ib := MySQL.NewInsertBuilder()
+	ib.InsertInto("t").Cols("a").Values(1).DefaultValues()
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO t () VALUES ()"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
+
+func TestDefaultValuesNonMySQL(t *testing.T) {
+	ib := PostgreSQL.NewInsertBuilder()
+	ib.InsertInto("t").DefaultValues()
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO t DEFAULT VALUES"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
+
+func TestSelectClearsDefaultValues(t *testing.T) {
+	sb := NewSelectBuilder()
+	sb.Select("a").From("src")
+
+	ib := NewInsertBuilder()
+	ib.InsertInto("dst").DefaultValues().Cols("a").Select(sb)
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO dst (a) SELECT a FROM src"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q; Select should clear a pending DefaultValues", sql, want)
+	}
+}
+
+type batchItemFixture struct {
+	ID    int    `db:"id;primary_key;auto_increment"`
+	Name  string `db:"name"`
+	Nick  string `db:"nick;omitempty"`
+	Email string `db:"email;omitempty;default=''"`
+}
+
+// TestInsertItemsStructBatchColumnAlignment pins that InsertItems keeps
+// every row the same width even when "omitempty" fields are zero on some
+// rows but not others: a naive per-row insertItemFields would otherwise
+// shorten that row's VALUES tuple and misalign every later column.
+func TestInsertItemsStructBatchColumnAlignment(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("t")
+	ib.InsertItems([]batchItemFixture{
+		{Name: "alice", Nick: "ali", Email: "alice@example.com"},
+		{Name: "bob"},
+	})
+
+	sql, args := ib.Build()
+
+	want := "INSERT INTO t (name, nick, email) VALUES (?, ?, ?), (?, DEFAULT, DEFAULT)"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+
+	wantArgs := []interface{}{"alice", "ali", "alice@example.com", "bob"}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("args = %v, want %v", args, wantArgs)
+	}
+	for i := range wantArgs {
+		if args[i] != wantArgs[i] {
+			t.Fatalf("args = %v, want %v (row/column misaligned at index %d)", args, wantArgs, i)
+		}
+	}
+}
+
+// TestInsertItemsStructBatchOmitEmptyNoDefault pins that an "omitempty"
+// field with no "default=..." still keeps its column across the batch
+// (unlike the single-row InsertItem, which omits it), filling zero values
+// with the bare Default sentinel to preserve row width.
+func TestInsertItemsStructBatchOmitEmptyNoDefault(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("t")
+	ib.InsertItems([]batchItemFixture{
+		{Name: "alice", Nick: "ali"},
+		{Name: "bob"},
+	})
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO t (name, nick, email) VALUES (?, ?, DEFAULT), (?, DEFAULT, DEFAULT)"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
+
+func TestInsertValuesClearsSelect(t *testing.T) {
+	ib := NewInsertBuilder()
+	ib.InsertInto("dst").Cols("a").From("src").Values(1)
+
+	sql, _ := ib.Build()
+
+	want := "INSERT INTO dst (a) VALUES (?)"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q; Values should clear the pending Select", sql, want)
+	}
+}
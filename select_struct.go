@@ -0,0 +1,27 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import "reflect"
+
+// SelectStruct sets sb's SELECT column list from item's "db" tags, using
+// the same DefaultMetaRegistry InsertItem/InsertItems read, so sb and an
+// InsertBuilder built from the same struct type always agree on column
+// order. item is typically a pointer to a zero value, e.g.
+// sb.SelectStruct(&User{}). Fields tagged primary_key/auto_increment are
+// still selected; only InsertItem/InsertItems skip those.
+func (sb *SelectBuilder) SelectStruct(item interface{}) *SelectBuilder {
+	t := reflect.TypeOf(item)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	meta := DefaultMetaRegistry.MetaFor(t)
+	cols := make([]string, len(meta.Fields))
+	for i, fm := range meta.Fields {
+		cols[i] = fm.Column
+	}
+
+	return sb.Select(cols...)
+}
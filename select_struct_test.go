@@ -0,0 +1,18 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import "testing"
+
+func TestSelectStructUsesDbTags(t *testing.T) {
+	sb := NewSelectBuilder()
+	sb.SelectStruct(&insertItemFixture{}).From("t")
+
+	sql, _ := sb.Build()
+
+	want := "SELECT id, serial, name, nick FROM t"
+	if sql != want {
+		t.Fatalf("sql = %q, want %q", sql, want)
+	}
+}
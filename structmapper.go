@@ -0,0 +1,120 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// StructFieldMeta describes how a single struct field maps to a column.
+type StructFieldMeta struct {
+	Column        string
+	PrimaryKey    bool
+	AutoIncrement bool
+	OmitEmpty     bool
+	// Default is the value of a "default=..." tag option. It marks a field
+	// whose zero value, under OmitEmpty or OmitZero, should still occupy
+	// its column (rendered as the Default sentinel) instead of being
+	// omitted -- see fieldInsertValue. The option's text itself isn't used;
+	// its presence is the signal.
+	Default string
+	Index   []int
+}
+
+// StructMeta is the parsed column mapping for one struct type. Fields are
+// kept in declaration order, with embedded structs flattened in place.
+type StructMeta struct {
+	Type   reflect.Type
+	Fields []*StructFieldMeta
+}
+
+// MetaRegistry parses and caches StructMeta by reflect.Type, so repeated
+// InsertItem/InsertItems calls on the same struct type don't re-parse tags
+// every time. The zero value is not usable; create one with NewMetaRegistry.
+type MetaRegistry struct {
+	tagName string
+	cache   sync.Map // reflect.Type -> *StructMeta
+}
+
+// NewMetaRegistry creates a MetaRegistry that reads struct tags named
+// tagName, e.g. "db", "gorm" or "xorm".
+func NewMetaRegistry(tagName string) *MetaRegistry {
+	return &MetaRegistry{tagName: tagName}
+}
+
+// DefaultMetaRegistry is the MetaRegistry InsertItem/InsertItems use unless
+// a builder is given its own via InsertBuilder.WithTagName.
+var DefaultMetaRegistry = NewMetaRegistry("db")
+
+// RegisterType registers a pre-built StructMeta for t, bypassing tag
+// parsing entirely. Useful when a mapping can't be expressed with tags
+// alone.
+func (r *MetaRegistry) RegisterType(t reflect.Type, meta *StructMeta) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r.cache.Store(t, meta)
+}
+
+// MetaFor returns the StructMeta for t, parsing and caching it on first
+// use.
+func (r *MetaRegistry) MetaFor(t reflect.Type) *StructMeta {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if cached, ok := r.cache.Load(t); ok {
+		return cached.(*StructMeta)
+	}
+
+	meta := r.parse(t, nil)
+	actual, _ := r.cache.LoadOrStore(t, meta)
+	return actual.(*StructMeta)
+}
+
+func (r *MetaRegistry) parse(t reflect.Type, index []int) *StructMeta {
+	meta := &StructMeta{Type: t}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			embedded := r.parse(f.Type, fieldIndex)
+			meta.Fields = append(meta.Fields, embedded.Fields...)
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup(r.tagName)
+		if !ok || tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ";")
+		fm := &StructFieldMeta{Column: opts[0], Index: fieldIndex}
+		if fm.Column == "" {
+			continue
+		}
+
+		for _, opt := range opts[1:] {
+			switch {
+			case opt == "primary_key":
+				fm.PrimaryKey = true
+			case opt == "auto_increment":
+				fm.AutoIncrement = true
+			case opt == "omitempty":
+				fm.OmitEmpty = true
+			case strings.HasPrefix(opt, "default="):
+				fm.Default = strings.TrimPrefix(opt, "default=")
+			}
+		}
+
+		meta.Fields = append(meta.Fields, fm)
+	}
+
+	return meta
+}
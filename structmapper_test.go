@@ -0,0 +1,103 @@
+// Copyright 2018 Huan Du. All rights reserved.
+// Licensed under the MIT license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"reflect"
+	"testing"
+)
+
+type insertItemFixture struct {
+	ID      int    `db:"id;primary_key"`
+	Serial  int    `db:"serial;primary_key;auto_increment"`
+	Name    string `db:"name"`
+	Nick    string `db:"nick;omitempty"`
+	Ignored string `db:"-"`
+	skipped string
+}
+
+// TestInsertItemFieldsSkipsPrimaryKey pins the pre-MetaRegistry behavior:
+// a primary_key field is never inserted, whether or not it's also
+// auto_increment.
+func TestInsertItemFieldsSkipsPrimaryKey(t *testing.T) {
+	ib := newInsertBuilder()
+	item := insertItemFixture{ID: 1, Serial: 2, Name: "alice", Nick: ""}
+
+	cols, values := ib.insertItemFields(item)
+
+	wantCols := []string{"name"}
+	if !reflect.DeepEqual(cols, wantCols) {
+		t.Fatalf("cols = %v, want %v", cols, wantCols)
+	}
+	if !reflect.DeepEqual(values, []interface{}{"alice"}) {
+		t.Fatalf("values = %v, want [alice]", values)
+	}
+}
+
+func TestInsertItemFieldsOmitEmpty(t *testing.T) {
+	ib := newInsertBuilder()
+	item := insertItemFixture{ID: 1, Name: "bob", Nick: "bobby"}
+
+	cols, _ := ib.insertItemFields(item)
+
+	found := false
+	for _, c := range cols {
+		if c == "nick" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("cols = %v, want nick present when non-zero", cols)
+	}
+}
+
+func TestInsertItemFieldsOmitZero(t *testing.T) {
+	ib := newInsertBuilder()
+	ib.OmitZero()
+	item := insertItemFixture{ID: 1, Name: ""}
+
+	cols, _ := ib.insertItemFields(item)
+
+	for _, c := range cols {
+		if c == "name" {
+			t.Fatalf("cols = %v, want name omitted for zero value under OmitZero", cols)
+		}
+	}
+}
+
+type defaultTagFixture struct {
+	ID      int    `db:"id;primary_key;auto_increment"`
+	Name    string `db:"name"`
+	Created string `db:"created;omitempty;default=now()"`
+}
+
+// TestInsertItemFieldsUsesDefaultTag pins that a zero-valued "omitempty"
+// field tagged "default=..." keeps its column, with Default substituted
+// for the zero value, instead of being silently dropped.
+func TestInsertItemFieldsUsesDefaultTag(t *testing.T) {
+	ib := newInsertBuilder()
+	item := defaultTagFixture{Name: "alice"}
+
+	cols, values := ib.insertItemFields(item)
+
+	wantCols := []string{"name", "created"}
+	if !reflect.DeepEqual(cols, wantCols) {
+		t.Fatalf("cols = %v, want %v", cols, wantCols)
+	}
+	if !reflect.DeepEqual(values, []interface{}{"alice", Default}) {
+		t.Fatalf("values = %v, want [alice Default]", values)
+	}
+}
+
+func TestMetaRegistryCachesByType(t *testing.T) {
+	r := NewMetaRegistry("db")
+	typ := reflect.TypeOf(insertItemFixture{})
+
+	m1 := r.MetaFor(typ)
+	m2 := r.MetaFor(typ)
+
+	if m1 != m2 {
+		t.Fatalf("MetaFor should return the cached *StructMeta on the second call")
+	}
+}